@@ -21,9 +21,11 @@
 package hp437
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"hz.tools/gpib"
 )
@@ -33,6 +35,55 @@ type Device struct {
 	*gpib.Device
 }
 
+// 437B status byte bits, as returned by a serial poll.
+const (
+	// statusByteDataReady indicates a new measurement is available to
+	// be read.
+	statusByteDataReady = 0x01
+
+	// statusByteSyntaxError indicates the last command sent was not
+	// understood.
+	statusByteSyntaxError = 0x02
+
+	// statusByteHardwareError indicates a hardware fault, such as an
+	// input overload.
+	statusByteHardwareError = 0x04
+
+	// statusByteCalDone indicates a zero or calibration cycle has
+	// completed.
+	statusByteCalDone = 0x08
+)
+
+// waitReadyPollInterval is how often WaitReady polls the status byte.
+const waitReadyPollInterval = 50 * time.Millisecond
+
+// WaitReady blocks until the 437B reports a fresh reading is available,
+// or that a zero/cal cycle has completed, by polling its status byte.
+// This lets a caller do a triggered measurement without racing Read
+// against the meter's own settle time.
+func (dev Device) WaitReady(ctx context.Context) error {
+	for {
+		spr, err := dev.SerialPoll()
+		if err != nil {
+			return err
+		}
+		if spr&statusByteHardwareError != 0 {
+			return fmt.Errorf("hp437: hardware error reported by status byte")
+		}
+		if spr&statusByteSyntaxError != 0 {
+			return fmt.Errorf("hp437: syntax error reported by status byte")
+		}
+		if spr&(statusByteDataReady|statusByteCalDone) != 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitReadyPollInterval):
+		}
+	}
+}
+
 // Reset will do a soft-reset of the power meter.
 func (dev Device) Reset() error {
 	_, err := dev.Write([]byte("*RST\r\n"))
@@ -98,6 +149,9 @@ func (dev Device) Power() (float64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if i == len(buf) && !dev.Status().End() {
+		return 0, fmt.Errorf("hp437: reading was truncated before the end of the message")
+	}
 	buf = buf[:i]
 
 	reading := strings.TrimSpace(string(buf[:i]))
@@ -0,0 +1,139 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package hpsa drives the HP 8566/8568 family of spectrum analyzers over
+// the GPIB / HP-IB.
+package hpsa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"hz.tools/gpib"
+)
+
+// Device represents a HP 8566/8568-family spectrum analyzer to be used
+// over the GPIB / HP-IB.
+type Device struct {
+	*gpib.Device
+}
+
+// SetCenterFrequency sets the center frequency of the displayed span, in
+// Hz.
+func (dev Device) SetCenterFrequency(hz float64) error {
+	_, err := dev.Write([]byte(fmt.Sprintf("CF%fHZ\r\n", hz)))
+	return err
+}
+
+// SetSpan sets the width of the displayed span, in Hz.
+func (dev Device) SetSpan(hz float64) error {
+	_, err := dev.Write([]byte(fmt.Sprintf("SP%fHZ\r\n", hz)))
+	return err
+}
+
+// SetResolutionBandwidth sets the resolution bandwidth, in Hz.
+func (dev Device) SetResolutionBandwidth(hz float64) error {
+	_, err := dev.Write([]byte(fmt.Sprintf("RB%fHZ\r\n", hz)))
+	return err
+}
+
+// SetVideoBandwidth sets the video bandwidth, in Hz.
+func (dev Device) SetVideoBandwidth(hz float64) error {
+	_, err := dev.Write([]byte(fmt.Sprintf("VB%fHZ\r\n", hz)))
+	return err
+}
+
+// SetReferenceLevel sets the reference level at the top of the display,
+// in dBm.
+func (dev Device) SetReferenceLevel(dbm float64) error {
+	_, err := dev.Write([]byte(fmt.Sprintf("RL%fDB\r\n", dbm)))
+	return err
+}
+
+// MarkerPeak places the active marker on the highest point of the
+// trace.
+func (dev Device) MarkerPeak() error {
+	_, err := dev.Write([]byte("MKPK HI\r\n"))
+	return err
+}
+
+// readFloat issues cmd and parses the single floating point value it
+// returns.
+func (dev Device) readFloat(cmd string) (float64, error) {
+	if _, err := dev.Write([]byte(cmd)); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1024)
+	i, err := dev.Read(buf)
+	if err != nil {
+		return 0, err
+	}
+	if i == len(buf) && !dev.Status().End() {
+		return 0, fmt.Errorf("hpsa: reading was truncated before the end of the message")
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(buf[:i])), 64)
+}
+
+// MarkerAmplitude returns the amplitude of the active marker, in the
+// currently configured amplitude units.
+func (dev Device) MarkerAmplitude() (float64, error) {
+	return dev.readFloat("MA\r\n")
+}
+
+// MarkerFrequency returns the frequency of the active marker, in Hz.
+func (dev Device) MarkerFrequency() (float64, error) {
+	return dev.readFloat("MF\r\n")
+}
+
+// Trace reads back the currently displayed trace as a slice of
+// amplitude values, one per display point, in the currently configured
+// amplitude units.
+func (dev Device) Trace() ([]float64, error) {
+	if _, err := dev.Write([]byte("TA\r\n")); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 16384)
+	i, err := dev.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	if i == len(buf) && !dev.Status().End() {
+		return nil, fmt.Errorf("hpsa: reading was truncated before the end of the message")
+	}
+	fields := strings.Split(strings.TrimSpace(string(buf[:i])), ",")
+	trace := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("hpsa: failed to parse trace point %d: %w", i, err)
+		}
+		trace[i] = v
+	}
+	return trace, nil
+}
+
+// New will create a new hpsa.Device to use a spectrum analyzer over
+// HP-IB.
+func New(dev *gpib.Device) Device {
+	return Device{dev}
+}
+
+// vim: foldmethod=marker
@@ -20,20 +20,10 @@
 
 package gpib
 
-// TODO(paultag): no pkg-config for gpib yet, so we need to manually set
-// the linker using LDFLAGS.
-
-// #cgo LDFLAGS: -lgpib
-//
-// #include <stdlib.h>
-// #include <gpib/ib.h>
-import "C"
-
 import (
 	"context"
 	"fmt"
-	"syscall"
-	"unsafe"
+	"time"
 )
 
 // Options contains configurable aspects of the connected GPIB device.
@@ -42,9 +32,61 @@ type Options struct {
 	// as the underlying handle to the remote GPIB device.
 	BaseContext context.Context
 
-	// Timeout time.Time
-	// SendEOI
-	// EOS
+	// Timeout bounds how long an individual GPIB I/O call may block
+	// before giving up, if the Transport supports it.
+	Timeout time.Duration
+
+	// SendEOI asserts the EOI line on the last byte of a Write, if the
+	// Transport supports it.
+	SendEOI bool
+
+	// EOS configures the end-of-string terminator character used to
+	// delimit reads and/or writes, if the Transport supports it.
+	EOS *EOS
+}
+
+// EOSMode controls how the end-of-string character configured via
+// Options.EOS or Device.SetEOS is interpreted.
+type EOSMode int
+
+const (
+	// EOSReceive enables end-of-string character detection on read.
+	EOSReceive EOSMode = 1 << iota
+
+	// EOSTransmitEOI asserts EOI when the end-of-string character is
+	// written.
+	EOSTransmitEOI
+
+	// EOSBinary compares all 8 bits of the end-of-string character,
+	// rather than just the low 7.
+	EOSBinary
+)
+
+// EOS configures the end-of-string character used to terminate reads
+// and/or writes.
+type EOS struct {
+	Char byte
+	Mode EOSMode
+}
+
+// Timeouter is implemented by Transports that support adjusting the
+// per-call I/O timeout, such as the linux-gpib backend.
+type Timeouter interface {
+	SetTimeout(time.Duration) error
+}
+
+// EOSSetter is implemented by Transports that support configuring an
+// end-of-string terminator character, such as the linux-gpib backend.
+type EOSSetter interface {
+	SetEOS(EOS) error
+}
+
+// ContextSetter is implemented by Transports that want visibility into
+// the owning Device's lifetime context, so that in-flight I/O can be
+// canceled along with it, such as the linux-gpib backend's async
+// ibrda/ibwrta/ibstop path.
+type ContextSetter interface {
+	SetContext(ctx context.Context)
 }
 
 func (opts *Options) context() context.Context {
@@ -54,58 +96,32 @@ func (opts *Options) context() context.Context {
 	return opts.BaseContext
 }
 
-// Device represents a device connected to the GPIB.
+// Device represents a device connected to the GPIB. A Device is a thin
+// wrapper around a Transport -- it owns the lifecycle (the Context, and
+// closing things down), and leaves actually moving bytes and twiddling
+// bus control lines to the Transport.
 type Device struct {
-	ctx        context.Context
-	cancel     context.CancelFunc
-	closed     bool
-	descriptor C.int
-}
-
-type status int
-
-func getiberr() error {
-	switch C.iberr {
-	case 0:
-		return syscall.Errno(C.ibcnt)
-	case 1:
-		return fmt.Errorf("gpib: interface board needs to be controller-in-charge, but is not")
-	case 2:
-		return fmt.Errorf("gpib: attempted to write data or command bytes, but there are no listeners currently addressed")
-	case 3:
-		return fmt.Errorf("gpib: interface board has failed to address itself properly before starting an io operation")
-	case 4:
-		return fmt.Errorf("gpib: arguments to the function call were invalid")
-	case 5:
-		return fmt.Errorf("gpib: interface board needs to be system controller, but is not")
-	case 6:
-		return fmt.Errorf("gpib: read or write of data bytes has been aborted")
-	case 7:
-		return fmt.Errorf("gpib: interface board does not exist")
-	case 10:
-		return fmt.Errorf("gpib: function call can not proceed due to an asynchronous IO operation")
-	case 11:
-		return fmt.Errorf("gpib: GPIB board lacks desired capability")
-	case 12:
-		// filesystem error
-		return syscall.Errno(C.ibcnt)
-	case 14:
-		// TODO(paultag): return a named timeout error here
-		return fmt.Errorf("gpib: attempt to write command bytes to the bus has timed out")
-	case 15:
-		return fmt.Errorf("gpib: serial poll status bytes have been lost")
-	case 16:
-		return fmt.Errorf("gpib: serial poll request service line is stuck on")
-	default:
-		return fmt.Errorf("gpib: unknown error")
-	}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closed    bool
+	transport Transport
 }
 
-func (s status) Err() error {
-	if s&0x8000 == 0x8000 {
-		return getiberr()
+// NewDevice will wrap a Transport in a Device, so that it can be used
+// by hp437.Device and other instrument drivers. Most callers will want
+// Open (which builds a Transport talking to linux-gpib) rather than
+// calling this directly; NewDevice exists so alternate Transports, such
+// as a Prologix controller, can be plugged in the same way.
+func NewDevice(transport Transport, opts *Options) *Device {
+	ctx, cancel := context.WithCancel(opts.context())
+	if cs, ok := transport.(ContextSetter); ok {
+		cs.SetContext(ctx)
+	}
+	return &Device{
+		ctx:       ctx,
+		cancel:    cancel,
+		transport: transport,
 	}
-	return nil
 }
 
 // Close will release the underlying handle to the GPIB device, and close
@@ -115,8 +131,7 @@ func (d *Device) Close() error {
 		return nil
 	}
 	d.cancel()
-	rv := C.ibonl(d.descriptor, 0)
-	if err := status(rv).Err(); err != nil {
+	if err := d.transport.Close(); err != nil {
 		return err
 	}
 	d.closed = true
@@ -125,8 +140,7 @@ func (d *Device) Close() error {
 
 // Local will return local control to the user over the device.
 func (d *Device) Local() error {
-	rv := C.ibloc(d.descriptor)
-	return status(rv).Err()
+	return d.transport.Local()
 }
 
 // func (d *Device) Remote(enable bool) error {
@@ -138,47 +152,75 @@ func (d *Device) Local() error {
 // 	return status(rv).Err()
 // }
 
+// Trigger will issue a GPIB group execute trigger to the device.
+func (d *Device) Trigger() error {
+	return d.transport.Trigger()
+}
+
+// Timeout sets how long subsequent I/O calls against this Device may
+// block before giving up, if the underlying Transport supports it.
+func (d *Device) Timeout(timeout time.Duration) error {
+	t, ok := d.transport.(Timeouter)
+	if !ok {
+		return fmt.Errorf("gpib: transport does not support setting a timeout")
+	}
+	return t.SetTimeout(timeout)
+}
+
+// SetEOS configures the end-of-string terminator character used by this
+// Device, if the underlying Transport supports it.
+func (d *Device) SetEOS(eos byte, mode EOSMode) error {
+	t, ok := d.transport.(EOSSetter)
+	if !ok {
+		return fmt.Errorf("gpib: transport does not support setting an EOS character")
+	}
+	return t.SetEOS(EOS{Char: eos, Mode: mode})
+}
+
+// applyDeadline pushes the Device's context deadline, if any, down to
+// the Transport as a per-call timeout, so a Read or Write can't hang
+// past a caller-supplied context.WithTimeout/WithDeadline.
+func (d *Device) applyDeadline() error {
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	deadline, ok := d.ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	t, ok := d.transport.(Timeouter)
+	if !ok {
+		return nil
+	}
+	return t.SetTimeout(time.Until(deadline))
+}
+
 // Write will write user data to the GPIB device.
 func (d *Device) Write(buf []byte) (int, error) {
-	cb := C.CBytes(buf)
-	defer C.free(unsafe.Pointer(cb))
-	rv := C.ibwrt(d.descriptor, cb, C.long(len(buf)))
-	if err := status(rv).Err(); err != nil {
+	if err := d.applyDeadline(); err != nil {
 		return 0, err
 	}
-	return len(buf), nil
+	return d.transport.Write(buf)
 }
 
 // Read will read data from the GPIB device.
 func (d *Device) Read(buf []byte) (int, error) {
-	var (
-		cbuflen = C.size_t(len(buf))
-		cbuf    = C.malloc(cbuflen)
-	)
-	// TODO(paultag): Need to check the RV here.
-	rv := C.ibrd(d.descriptor, cbuf, C.long(cbuflen))
-	if err := status(rv).Err(); err != nil {
+	if err := d.applyDeadline(); err != nil {
 		return 0, err
 	}
-
-	leng := C.ibcntl
-	i := copy(buf, C.GoBytes(cbuf, C.int(leng)))
-	return i, nil
+	return d.transport.Read(buf)
 }
 
-// Open will open a provided GPIB device.
+// Open will open a provided GPIB device using the host's linux-gpib
+// install. board is the board index (as configured in gpib.conf), pad
+// is the device's primary address, and sad is the secondary address (or
+// 0 if the device doesn't use one).
 func Open(board, pad, sad int, opts *Options) (*Device, error) {
-	ctx, cancel := context.WithCancel(opts.context())
-
-	desc := C.ibdev(C.int(board), C.int(pad), C.int(sad), 0, 0, 0)
-	if desc == -1 {
-		return nil, fmt.Errorf("gpib: failed to open the specified device")
+	transport, err := newIBTransport(board, pad, sad, opts)
+	if err != nil {
+		return nil, err
 	}
-	return &Device{
-		ctx:        ctx,
-		cancel:     cancel,
-		descriptor: desc,
-	}, nil
+	return NewDevice(transport, opts), nil
 }
 
 // vim: foldmethod=marker
@@ -0,0 +1,347 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+// TODO(paultag): no pkg-config for gpib yet, so we need to manually set
+// the linker using LDFLAGS.
+
+// #cgo LDFLAGS: -lgpib
+//
+// #include <stdlib.h>
+// #include <gpib/ib.h>
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ibTransport is the Transport implementation backed by the host's
+// linux-gpib install. This is what Open builds and hands to NewDevice.
+type ibTransport struct {
+	descriptor C.int
+	lastStatus Status
+	ctx        context.Context
+}
+
+// SetContext implements ContextSetter, giving the transport visibility
+// into the owning Device's lifetime so that a pending asynchronous
+// Read/Write can be aborted when that context is canceled or the
+// Device is closed.
+func (t *ibTransport) SetContext(ctx context.Context) {
+	t.ctx = ctx
+}
+
+// ibTimeouts is linux-gpib's table of discrete timeout constants, in
+// ascending order, used to pick the smallest configured timeout that is
+// still >= the requested time.Duration.
+var ibTimeouts = []struct {
+	d time.Duration
+	c C.int
+}{
+	{10 * time.Microsecond, C.T10us},
+	{30 * time.Microsecond, C.T30us},
+	{100 * time.Microsecond, C.T100us},
+	{300 * time.Microsecond, C.T300us},
+	{1 * time.Millisecond, C.T1ms},
+	{3 * time.Millisecond, C.T3ms},
+	{10 * time.Millisecond, C.T10ms},
+	{30 * time.Millisecond, C.T30ms},
+	{100 * time.Millisecond, C.T100ms},
+	{300 * time.Millisecond, C.T300ms},
+	{1 * time.Second, C.T1s},
+	{3 * time.Second, C.T3s},
+	{10 * time.Second, C.T10s},
+	{30 * time.Second, C.T30s},
+	{100 * time.Second, C.T100s},
+	{300 * time.Second, C.T300s},
+	{1000 * time.Second, C.T1000s},
+}
+
+// ibTimeoutIndex returns the index into ibTimeouts of the smallest
+// linux-gpib timeout bucket that is still >= d, or -1 for d <= 0 (no
+// timeout). A d above the largest bucket is clamped to the largest
+// bucket. It's factored out of ibTimeout so the bucket-selection logic
+// can be unit tested without cgo.
+func ibTimeoutIndex(d time.Duration) int {
+	if d <= 0 {
+		return -1
+	}
+	for i, t := range ibTimeouts {
+		if d <= t.d {
+			return i
+		}
+	}
+	return len(ibTimeouts) - 1
+}
+
+// ibTimeout maps a time.Duration onto the smallest linux-gpib timeout
+// constant that is still >= d (clamped to C.T1000s), or C.TNONE for
+// d <= 0 (no timeout).
+func ibTimeout(d time.Duration) C.int {
+	i := ibTimeoutIndex(d)
+	if i < 0 {
+		return C.TNONE
+	}
+	return ibTimeouts[i].c
+}
+
+// eosFlags reports which of the REOS/XEOS/BIN bits packEOS should set for
+// mode. It's factored out of packEOS so the mode-to-flag mapping can be
+// unit tested without cgo.
+func eosFlags(mode EOSMode) (receive, transmitEOI, binary bool) {
+	return mode&EOSReceive != 0, mode&EOSTransmitEOI != 0, mode&EOSBinary != 0
+}
+
+// packEOS packs an EOS character and its mode flags into the format
+// ibeos/the eos argument of ibdev expects: the character in the low
+// byte, with REOS/XEOS/BIN set in the high bits as configured.
+func packEOS(eos EOS) C.int {
+	v := C.int(eos.Char)
+	receive, transmitEOI, binary := eosFlags(eos.Mode)
+	if receive {
+		v |= C.REOS
+	}
+	if transmitEOI {
+		v |= C.XEOS
+	}
+	if binary {
+		v |= C.BIN
+	}
+	return v
+}
+
+// getiberr translates the global linux-gpib "iberr" code, set whenever
+// StatusERR is seen in ibsta, into one of the Err* sentinel errors.
+func getiberr() error {
+	switch C.iberr {
+	case C.EDVR:
+		return fmt.Errorf("%w: %s", ErrEDVR, syscall.Errno(C.ibcnt))
+	case C.ECIC:
+		return ErrECIC
+	case C.ENOL:
+		return ErrENOL
+	case C.EADR:
+		return ErrEADR
+	case C.EARG:
+		return ErrEARG
+	case C.ESAC:
+		return ErrESAC
+	case C.EABO:
+		return ErrEABO
+	case C.ENEB:
+		return ErrENEB
+	case C.EDMA:
+		return ErrEDMA
+	case C.EOIP:
+		return ErrEOIP
+	case C.ECAP:
+		return ErrECAP
+	case C.EFSO:
+		return fmt.Errorf("%w: %s", ErrEFSO, syscall.Errno(C.ibcnt))
+	case C.EBUS:
+		return ErrEBUS
+	case C.ETMO:
+		return ErrETMO
+	case C.ESTB:
+		return ErrESTB
+	case C.ESRQ:
+		return ErrESRQ
+	case C.ETAB:
+		return ErrETAB
+	default:
+		return fmt.Errorf("gpib: unknown iberr %d", int(C.iberr))
+	}
+}
+
+// record remembers rv as the ibTransport's last Status, and returns the
+// error it carries (if any), so every cgo call site reports the same
+// way.
+func (t *ibTransport) record(rv C.int) error {
+	t.lastStatus = Status(rv)
+	return t.lastStatus.Err()
+}
+
+// Status implements StatusProvider.
+func (t *ibTransport) Status() Status {
+	return t.lastStatus
+}
+
+// newIBTransport opens a GPIB device against the host's linux-gpib install.
+func newIBTransport(board, pad, sad int, opts *Options) (*ibTransport, error) {
+	var (
+		timeout C.int = C.TNONE
+		sendEOI C.int
+		eos     C.int
+	)
+	if opts != nil {
+		timeout = ibTimeout(opts.Timeout)
+		if opts.SendEOI {
+			sendEOI = 1
+		}
+		if opts.EOS != nil {
+			eos = packEOS(*opts.EOS)
+		}
+	}
+	desc := C.ibdev(C.int(board), C.int(pad), C.int(sad), timeout, sendEOI, eos)
+	if desc == -1 {
+		return nil, fmt.Errorf("gpib: failed to open the specified device")
+	}
+	return &ibTransport{descriptor: desc}, nil
+}
+
+// SetTimeout implements Timeouter.
+func (t *ibTransport) SetTimeout(d time.Duration) error {
+	rv := C.ibtmo(t.descriptor, ibTimeout(d))
+	return t.record(rv)
+}
+
+// SetEOS implements EOSSetter.
+func (t *ibTransport) SetEOS(eos EOS) error {
+	rv := C.ibeos(t.descriptor, packEOS(eos))
+	return t.record(rv)
+}
+
+// Close implements Transport.
+func (t *ibTransport) Close() error {
+	rv := C.ibonl(t.descriptor, 0)
+	return t.record(rv)
+}
+
+// Local implements Transport.
+func (t *ibTransport) Local() error {
+	rv := C.ibloc(t.descriptor)
+	return t.record(rv)
+}
+
+// Trigger implements Transport.
+func (t *ibTransport) Trigger() error {
+	rv := C.ibtrg(t.descriptor)
+	return t.record(rv)
+}
+
+// ClearDevice implements DeviceClearer.
+func (t *ibTransport) ClearDevice() error {
+	rv := C.ibclr(t.descriptor)
+	return t.record(rv)
+}
+
+// WaitSRQ implements SRQWaiter, blocking in a goroutine on ibwait for
+// either RQS (a service request) or TIMO (the device's configured
+// timeout elapsing) to come in, honoring ctx cancellation.
+func (t *ibTransport) WaitSRQ(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		rv := C.ibwait(t.descriptor, C.RQS|C.TIMO)
+		done <- t.record(rv)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		if t.lastStatus.Timeout() {
+			return fmt.Errorf("gpib: %w waiting for SRQ", ErrETMO)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SerialPoll implements Transport.
+func (t *ibTransport) SerialPoll() (byte, error) {
+	var spr C.char
+	rv := C.ibrsp(t.descriptor, &spr)
+	if err := t.record(rv); err != nil {
+		return 0, err
+	}
+	return byte(spr), nil
+}
+
+// waitAsync blocks until the asynchronous operation just launched with
+// ibwrta/ibrda completes, by waiting on ibwait(CMPL) in a goroutine. If
+// t.ctx is canceled first, ibstop aborts the pending operation; either
+// way, this always waits for ibwait(CMPL) to return before handing
+// control back, so the descriptor is left in a state safe to reuse.
+func (t *ibTransport) waitAsync() error {
+	ctx := t.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	done := make(chan C.int, 1)
+	go func() {
+		done <- C.ibwait(t.descriptor, C.CMPL)
+	}()
+	select {
+	case rv := <-done:
+		return t.record(rv)
+	case <-ctx.Done():
+		C.ibstop(t.descriptor)
+		t.record(<-done)
+		return ctx.Err()
+	}
+}
+
+// Write implements Transport.
+func (t *ibTransport) Write(buf []byte) (int, error) {
+	cb := C.CBytes(buf)
+	defer C.free(unsafe.Pointer(cb))
+	rv := C.ibwrta(t.descriptor, cb, C.long(len(buf)))
+	if err := t.record(rv); err != nil {
+		return 0, err
+	}
+	if err := t.waitAsync(); err != nil {
+		return 0, err
+	}
+	if err := t.lastStatus.Err(); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Read implements Transport.
+func (t *ibTransport) Read(buf []byte) (int, error) {
+	var (
+		cbuflen = C.size_t(len(buf))
+		cbuf    = C.malloc(cbuflen)
+	)
+	defer C.free(cbuf)
+	rv := C.ibrda(t.descriptor, cbuf, C.long(cbuflen))
+	if err := t.record(rv); err != nil {
+		return 0, err
+	}
+	if err := t.waitAsync(); err != nil {
+		return 0, err
+	}
+	if err := t.lastStatus.Err(); err != nil {
+		return 0, err
+	}
+
+	leng := C.ibcntl
+	i := copy(buf, C.GoBytes(cbuf, C.int(leng)))
+	return i, nil
+}
+
+// vim: foldmethod=marker
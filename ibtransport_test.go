@@ -0,0 +1,85 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// This file intentionally avoids cgo (Go test files may not "import C"),
+// so it only exercises the pure-Go halves of ibTimeout and packEOS,
+// ibTimeoutIndex and eosFlags.
+
+package gpib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIBTimeoutIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want int
+	}{
+		{name: "zero duration means no timeout", d: 0, want: -1},
+		{name: "negative duration means no timeout", d: -time.Second, want: -1},
+		{name: "below the smallest bucket rounds up to it", d: time.Nanosecond, want: 0},
+		{name: "exactly on a bucket edge", d: 1 * time.Second, want: 10},
+		{name: "just under a bucket edge rounds up to it", d: 999 * time.Millisecond, want: 10},
+		{name: "just over a bucket edge rounds up to the next one", d: 1*time.Second + time.Nanosecond, want: 11},
+		{name: "above the largest bucket clamps to it", d: 24 * time.Hour, want: len(ibTimeouts) - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ibTimeoutIndex(tt.d); got != tt.want {
+				t.Fatalf("ibTimeoutIndex(%s) = %d, want %d", tt.d, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEOSFlags(t *testing.T) {
+	tests := []struct {
+		name                                     string
+		mode                                     EOSMode
+		wantReceive, wantTransmitEOI, wantBinary bool
+	}{
+		{name: "no mode bits set"},
+		{name: "receive only", mode: EOSReceive, wantReceive: true},
+		{name: "transmit EOI only", mode: EOSTransmitEOI, wantTransmitEOI: true},
+		{name: "binary only", mode: EOSBinary, wantBinary: true},
+		{
+			name:            "all modes combined",
+			mode:            EOSReceive | EOSTransmitEOI | EOSBinary,
+			wantReceive:     true,
+			wantTransmitEOI: true,
+			wantBinary:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receive, transmitEOI, binary := eosFlags(tt.mode)
+			if receive != tt.wantReceive || transmitEOI != tt.wantTransmitEOI || binary != tt.wantBinary {
+				t.Fatalf("eosFlags(%v) = (%v, %v, %v), want (%v, %v, %v)",
+					tt.mode, receive, transmitEOI, binary,
+					tt.wantReceive, tt.wantTransmitEOI, tt.wantBinary)
+			}
+		})
+	}
+}
+
+// vim: foldmethod=marker
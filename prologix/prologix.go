@@ -0,0 +1,197 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+// Package prologix implements a gpib.Transport driving a Prologix
+// GPIB-USB or GPIB-Ethernet controller, which reaches the instrument over
+// a plain serial port or TCP socket rather than through linux-gpib. This
+// is handy for cheap USB or LAN-attached controllers, which are a very
+// common instrument-lab setup. The package imports hz.tools/gpib, which
+// is built with cgo against the linux-gpib headers and library, so
+// building this package still needs cgo and a linux-gpib install.
+package prologix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+
+	"hz.tools/gpib"
+)
+
+// readTimeoutMS is the timeout (in milliseconds) the controller itself
+// will wait for a response to "++read eoi" before giving up and
+// returning whatever it has.
+const readTimeoutMS = 1000
+
+// esc is the Prologix controller's escape character. On the
+// serial/TCP control channel, CR, LF, ESC, and '+' are all meaningful
+// to the controller itself (line framing and "++" command detection),
+// so any of those bytes appearing in instrument payload must be
+// preceded by esc to be forwarded to the instrument literally.
+const esc = 0x1b
+
+// needsEscape reports whether b is one of the bytes the Prologix
+// controller treats as its own framing, and so must be escaped before
+// being written as instrument payload.
+func needsEscape(b byte) bool {
+	switch b {
+	case '\r', '\n', esc, '+':
+		return true
+	default:
+		return false
+	}
+}
+
+// transport implements gpib.Transport against a Prologix controller
+// reachable over conn, which may be a serial port or a TCP socket.
+type transport struct {
+	conn io.ReadWriteCloser
+	r    *bufio.Reader
+}
+
+// New wraps an already-open connection to a Prologix controller (for
+// example, a serial port opened with a platform serial library) as a
+// gpib.Device addressing pad (and, if non-zero, sad).
+func New(conn io.ReadWriteCloser, pad, sad int, opts *gpib.Options) (*gpib.Device, error) {
+	t := &transport{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+	if err := t.init(pad, sad); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return gpib.NewDevice(t, opts), nil
+}
+
+// OpenTCP dials a Prologix GPIB-Ethernet controller at addr (host:port)
+// and returns a gpib.Device addressing pad (and, if non-zero, sad).
+func OpenTCP(addr string, pad, sad int, opts *gpib.Options) (*gpib.Device, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("prologix: %w", err)
+	}
+	return New(conn, pad, sad, opts)
+}
+
+// init puts the controller into CONTROLLER mode, addresses the target
+// device, turns off read-after-write ("++auto 0", so each Read sends its
+// own "++read eoi"), and asserts EOI on the last byte of each write.
+func (t *transport) init(pad, sad int) error {
+	addr := fmt.Sprintf("++addr %d", pad)
+	if sad != 0 {
+		addr = fmt.Sprintf("%s %d", addr, sad)
+	}
+	for _, cmd := range []string{
+		"++mode 1",
+		addr,
+		"++auto 0",
+		"++eoi 1",
+		fmt.Sprintf("++read_tmo_ms %d", readTimeoutMS),
+	} {
+		if err := t.cmd(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cmd sends a Prologix controller command (a "++"-prefixed line).
+func (t *transport) cmd(s string) error {
+	_, err := io.WriteString(t.conn, s+"\n")
+	return err
+}
+
+// Write implements gpib.Transport. Callers don't need to add their own
+// framing: any CR, LF, ESC, or '+' byte in buf is escaped so the
+// controller forwards it to the instrument literally, instead of
+// mistaking it for its own line terminator or a "++" command.
+func (t *transport) Write(buf []byte) (int, error) {
+	escaped := make([]byte, 0, len(buf))
+	for _, b := range buf {
+		if needsEscape(b) {
+			escaped = append(escaped, esc)
+		}
+		escaped = append(escaped, b)
+	}
+	if _, err := t.conn.Write(escaped); err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(t.conn, "\n"); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Read implements gpib.Transport. It asks the controller to read until
+// the device asserts EOI (or the controller's own read timeout trips),
+// then reads the line the controller hands back.
+func (t *transport) Read(buf []byte) (int, error) {
+	if err := t.cmd("++read eoi"); err != nil {
+		return 0, err
+	}
+	line, err := t.r.ReadBytes('\n')
+	if err != nil {
+		return 0, fmt.Errorf("prologix: read truncated before a terminator: %w", err)
+	}
+	line = bytes.TrimRight(line, "\r\n")
+	return copy(buf, line), nil
+}
+
+// Local implements gpib.Transport.
+func (t *transport) Local() error {
+	return t.cmd("++loc")
+}
+
+// ClearDevice implements gpib.DeviceClearer.
+func (t *transport) ClearDevice() error {
+	return t.cmd("++clr")
+}
+
+// Trigger implements gpib.Transport.
+func (t *transport) Trigger() error {
+	return t.cmd("++trg")
+}
+
+// SerialPoll implements gpib.Transport.
+func (t *transport) SerialPoll() (byte, error) {
+	if err := t.cmd("++spoll"); err != nil {
+		return 0, err
+	}
+	line, err := t.r.ReadBytes('\n')
+	if err != nil {
+		return 0, fmt.Errorf("prologix: read truncated before a terminator: %w", err)
+	}
+	line = bytes.TrimSpace(line)
+	var spr int
+	if _, err := fmt.Sscanf(string(line), "%d", &spr); err != nil {
+		return 0, fmt.Errorf("prologix: malformed serial poll response %q: %w", line, err)
+	}
+	return byte(spr), nil
+}
+
+// Close implements gpib.Transport.
+func (t *transport) Close() error {
+	return t.conn.Close()
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,173 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package prologix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeConn is an io.ReadWriteCloser standing in for a Prologix serial or
+// TCP connection: writes are captured verbatim, and reads are served from
+// a preloaded buffer so a truncated or dropped connection can be
+// simulated.
+type fakeConn struct {
+	written bytes.Buffer
+	toRead  *bytes.Reader
+	readErr error
+}
+
+func (c *fakeConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func (c *fakeConn) Read(p []byte) (int, error) {
+	if c.toRead.Len() == 0 && c.readErr != nil {
+		return 0, c.readErr
+	}
+	n, err := c.toRead.Read(p)
+	if err == io.EOF && c.readErr != nil {
+		err = c.readErr
+	}
+	return n, err
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func TestNeedsEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		b    byte
+		want bool
+	}{
+		{name: "carriage return", b: '\r', want: true},
+		{name: "line feed", b: '\n', want: true},
+		{name: "escape", b: esc, want: true},
+		{name: "plus", b: '+', want: true},
+		{name: "ordinary byte", b: 'A', want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsEscape(tt.b); got != tt.want {
+				t.Fatalf("needsEscape(%q) = %v, want %v", tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{
+			name: "no special bytes",
+			in:   []byte("FREQ?"),
+			want: []byte("FREQ?\n"),
+		},
+		{
+			name: "embedded CRLF is escaped",
+			in:   []byte("A\r\nB"),
+			want: []byte("A\x1b\r\x1b\nB\n"),
+		},
+		{
+			name: "embedded escape and plus are escaped",
+			in:   []byte("1+1\x1b"),
+			want: []byte("1\x1b+1\x1b\x1b\n"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConn{toRead: bytes.NewReader(nil)}
+			tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+			n, err := tr.Write(tt.in)
+			if err != nil {
+				t.Fatalf("Write: unexpected error: %v", err)
+			}
+			if n != len(tt.in) {
+				t.Fatalf("Write: returned %d, want %d", n, len(tt.in))
+			}
+			if !bytes.Equal(conn.written.Bytes(), tt.want) {
+				t.Fatalf("Write: wrote %q, want %q", conn.written.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportRead(t *testing.T) {
+	t.Run("clean line", func(t *testing.T) {
+		conn := &fakeConn{toRead: bytes.NewReader([]byte("hello\r\n"))}
+		tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+		buf := make([]byte, 32)
+		n, err := tr.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: unexpected error: %v", err)
+		}
+		if got := string(buf[:n]); got != "hello" {
+			t.Fatalf("Read: got %q, want %q", got, "hello")
+		}
+	})
+
+	t.Run("truncated read surfaces the underlying error", func(t *testing.T) {
+		conn := &fakeConn{
+			toRead:  bytes.NewReader([]byte("parti")),
+			readErr: errors.New("connection reset"),
+		}
+		tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+		buf := make([]byte, 32)
+		if _, err := tr.Read(buf); err == nil {
+			t.Fatal("Read: expected an error for a connection dropped mid-line, got none")
+		}
+	})
+}
+
+func TestTransportSerialPoll(t *testing.T) {
+	t.Run("clean response", func(t *testing.T) {
+		conn := &fakeConn{toRead: bytes.NewReader([]byte("64\r\n"))}
+		tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+		spr, err := tr.SerialPoll()
+		if err != nil {
+			t.Fatalf("SerialPoll: unexpected error: %v", err)
+		}
+		if spr != 64 {
+			t.Fatalf("SerialPoll: got %d, want 64", spr)
+		}
+	})
+
+	t.Run("truncated read surfaces the underlying error", func(t *testing.T) {
+		conn := &fakeConn{
+			toRead:  bytes.NewReader([]byte("6")),
+			readErr: errors.New("connection reset"),
+		}
+		tr := &transport{conn: conn, r: bufio.NewReader(conn)}
+		if _, err := tr.SerialPoll(); err == nil {
+			t.Fatal("SerialPoll: expected an error for a connection dropped mid-line, got none")
+		}
+	})
+}
+
+// vim: foldmethod=marker
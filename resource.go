@@ -0,0 +1,82 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseResource parses a VISA-style GPIB resource string, such as
+// "GPIB0::14::INSTR" or "GPIB1::14::96::INSTR" (with an optional
+// secondary address), into the board index, primary address (pad), and
+// secondary address (sad, or 0 if none was given).
+func ParseResource(resource string) (board, pad, sad int, err error) {
+	fields := strings.Split(resource, "::")
+	if len(fields) != 3 && len(fields) != 4 {
+		return 0, 0, 0, fmt.Errorf("gpib: malformed resource string %q", resource)
+	}
+	if fields[len(fields)-1] != "INSTR" {
+		return 0, 0, 0, fmt.Errorf("gpib: resource string %q is not an INSTR resource", resource)
+	}
+	if !strings.HasPrefix(fields[0], "GPIB") {
+		return 0, 0, 0, fmt.Errorf("gpib: resource string %q is not a GPIB resource", resource)
+	}
+
+	board, err = strconv.Atoi(strings.TrimPrefix(fields[0], "GPIB"))
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("gpib: malformed board index in resource string %q: %w", resource, err)
+	}
+
+	if pad, err = strconv.Atoi(fields[1]); err != nil {
+		return 0, 0, 0, fmt.Errorf("gpib: malformed primary address in resource string %q: %w", resource, err)
+	}
+	if pad < 0 || pad > 30 {
+		return 0, 0, 0, fmt.Errorf("gpib: primary address %d out of range [0, 30]", pad)
+	}
+
+	if len(fields) == 4 {
+		if sad, err = strconv.Atoi(fields[2]); err != nil {
+			return 0, 0, 0, fmt.Errorf("gpib: malformed secondary address in resource string %q: %w", resource, err)
+		}
+		if sad != 0 && (sad < 96 || sad > 126) {
+			return 0, 0, 0, fmt.Errorf("gpib: secondary address %d out of range {0} or [96, 126]", sad)
+		}
+	}
+
+	return board, pad, sad, nil
+}
+
+// OpenResource opens a GPIB device addressed with a VISA-style resource
+// string, such as "GPIB0::14::INSTR" or "GPIB1::14::96::INSTR", using
+// the host's linux-gpib install. This is the same portable syntax used
+// by NI-VISA/pyvisa, and is handy for plumbing configuration in from
+// files or CLI flags.
+func OpenResource(resource string, opts *Options) (*Device, error) {
+	board, pad, sad, err := ParseResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return Open(board, pad, sad, opts)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,138 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+import "testing"
+
+func TestParseResource(t *testing.T) {
+	tests := []struct {
+		name      string
+		resource  string
+		board     int
+		pad       int
+		sad       int
+		wantError bool
+	}{
+		{
+			name:     "primary address only",
+			resource: "GPIB0::14::INSTR",
+			board:    0,
+			pad:      14,
+		},
+		{
+			name:     "with secondary address",
+			resource: "GPIB1::14::96::INSTR",
+			board:    1,
+			pad:      14,
+			sad:      96,
+		},
+		{
+			name:     "secondary address at top of range",
+			resource: "GPIB0::1::126::INSTR",
+			board:    0,
+			pad:      1,
+			sad:      126,
+		},
+		{
+			name:     "explicit zero secondary address",
+			resource: "GPIB0::14::0::INSTR",
+			board:    0,
+			pad:      14,
+			sad:      0,
+		},
+		{
+			name:      "too few fields",
+			resource:  "GPIB0::14",
+			wantError: true,
+		},
+		{
+			name:      "too many fields",
+			resource:  "GPIB0::14::96::EXTRA::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "missing INSTR suffix",
+			resource:  "GPIB0::14::SOCKET",
+			wantError: true,
+		},
+		{
+			name:      "not a GPIB resource",
+			resource:  "TCPIP0::14::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "non-numeric board index",
+			resource:  "GPIBx::14::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "non-numeric primary address",
+			resource:  "GPIB0::x::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "primary address out of range",
+			resource:  "GPIB0::31::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "negative primary address",
+			resource:  "GPIB0::-1::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "non-numeric secondary address",
+			resource:  "GPIB0::14::x::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "secondary address below range",
+			resource:  "GPIB0::14::95::INSTR",
+			wantError: true,
+		},
+		{
+			name:      "secondary address above range",
+			resource:  "GPIB0::14::127::INSTR",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, pad, sad, err := ParseResource(tt.resource)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("ParseResource(%q): expected an error, got none", tt.resource)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseResource(%q): unexpected error: %v", tt.resource, err)
+			}
+			if board != tt.board || pad != tt.pad || sad != tt.sad {
+				t.Fatalf("ParseResource(%q) = (%d, %d, %d), want (%d, %d, %d)",
+					tt.resource, board, pad, sad, tt.board, tt.pad, tt.sad)
+			}
+		})
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,90 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// statusByteRQS is the bit of an IEEE-488 serial poll status byte that
+// indicates the polled device is the one that asserted SRQ.
+const statusByteRQS = 0x40
+
+// srqPollInterval is how often WaitSRQ polls SerialPoll when the
+// Transport doesn't implement SRQWaiter.
+const srqPollInterval = 10 * time.Millisecond
+
+// DeviceClearer is implemented by Transports that support sending a
+// GPIB selected device clear, such as the linux-gpib backend's ibclr
+// or a Prologix controller's "++clr".
+type DeviceClearer interface {
+	ClearDevice() error
+}
+
+// SRQWaiter is implemented by Transports that can block waiting for a
+// service request more efficiently than polling SerialPoll, such as
+// the linux-gpib backend's ibwait.
+type SRQWaiter interface {
+	WaitSRQ(ctx context.Context) error
+}
+
+// SerialPoll reads the device's serial poll status byte.
+func (d *Device) SerialPoll() (byte, error) {
+	return d.transport.SerialPoll()
+}
+
+// ClearDevice issues a GPIB selected device clear, if the underlying
+// Transport supports it.
+func (d *Device) ClearDevice() error {
+	t, ok := d.transport.(DeviceClearer)
+	if !ok {
+		return fmt.Errorf("gpib: transport does not support clearing the device")
+	}
+	return t.ClearDevice()
+}
+
+// WaitSRQ blocks until the device asserts a service request (SRQ), or
+// ctx is done. If the Transport implements SRQWaiter, that is used;
+// otherwise this falls back to polling SerialPoll and checking the RQS
+// bit of the status byte every srqPollInterval.
+func (d *Device) WaitSRQ(ctx context.Context) error {
+	if w, ok := d.transport.(SRQWaiter); ok {
+		return w.WaitSRQ(ctx)
+	}
+	for {
+		spr, err := d.SerialPoll()
+		if err != nil {
+			return err
+		}
+		if spr&statusByteRQS != 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(srqPollInterval):
+		}
+	}
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,219 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+import "errors"
+
+// Status is linux-gpib's "ibsta" status word, as left behind
+// by the most recent operation against a Device. It lets a caller
+// distinguish, for instance, a read that stopped because the instrument
+// asserted EOI (End) from one that was merely truncated.
+type Status int
+
+// Bits of the ibsta status word. See the linux-gpib documentation for
+// "ibsta" for the authoritative description of each.
+const (
+	// StatusDCAS indicates the board has received a device clear
+	// (device clear active state).
+	StatusDCAS Status = 0x0001
+
+	// StatusDTAS indicates the board has received a device trigger
+	// (device trigger active state).
+	StatusDTAS Status = 0x0002
+
+	// StatusLACS indicates the board is currently addressed as a
+	// listener.
+	StatusLACS Status = 0x0004
+
+	// StatusTACS indicates the board is currently addressed as a
+	// talker.
+	StatusTACS Status = 0x0008
+
+	// StatusATN indicates the ATN line is currently asserted.
+	StatusATN Status = 0x0010
+
+	// StatusCIC indicates the board is the controller-in-charge.
+	StatusCIC Status = 0x0020
+
+	// StatusREM indicates the board is in the remote state.
+	StatusREM Status = 0x0040
+
+	// StatusLOK indicates the board is in the lockout state.
+	StatusLOK Status = 0x0080
+
+	// StatusCMPL indicates the asynchronous I/O operation has
+	// completed.
+	StatusCMPL Status = 0x0100
+
+	// StatusRQS indicates a device is requesting service.
+	StatusRQS Status = 0x0800
+
+	// StatusSRQI indicates a service request has been detected.
+	StatusSRQI Status = 0x1000
+
+	// StatusEND indicates the END or EOS condition was detected on the
+	// most recent read.
+	StatusEND Status = 0x2000
+
+	// StatusTIMO indicates the operation timed out.
+	StatusTIMO Status = 0x4000
+
+	// StatusERR indicates the preceding operation returned an error;
+	// callers can use Status.Err to fetch it.
+	StatusERR Status = 0x8000
+)
+
+// Err returns the error that caused this Status, if StatusERR is set.
+func (s Status) Err() error {
+	if s&StatusERR != 0 {
+		return getiberr()
+	}
+	return nil
+}
+
+// ERR reports whether the preceding operation returned an error.
+func (s Status) ERR() bool { return s&StatusERR != 0 }
+
+// Timeout reports whether the preceding operation timed out.
+func (s Status) Timeout() bool { return s&StatusTIMO != 0 }
+
+// End reports whether the preceding read stopped because the instrument
+// asserted EOI, or the configured EOS character was seen.
+func (s Status) End() bool { return s&StatusEND != 0 }
+
+// SRQI reports whether a service request has been detected.
+func (s Status) SRQI() bool { return s&StatusSRQI != 0 }
+
+// RQS reports whether a device is requesting service.
+func (s Status) RQS() bool { return s&StatusRQS != 0 }
+
+// CMPL reports whether the asynchronous I/O operation has completed.
+func (s Status) CMPL() bool { return s&StatusCMPL != 0 }
+
+// LOK reports whether the board is in the lockout state.
+func (s Status) LOK() bool { return s&StatusLOK != 0 }
+
+// REM reports whether the board is in the remote state.
+func (s Status) REM() bool { return s&StatusREM != 0 }
+
+// CIC reports whether the board is the controller-in-charge.
+func (s Status) CIC() bool { return s&StatusCIC != 0 }
+
+// ATN reports whether the ATN line is currently asserted.
+func (s Status) ATN() bool { return s&StatusATN != 0 }
+
+// TACS reports whether the board is currently addressed as a talker.
+func (s Status) TACS() bool { return s&StatusTACS != 0 }
+
+// LACS reports whether the board is currently addressed as a listener.
+func (s Status) LACS() bool { return s&StatusLACS != 0 }
+
+// DTAS reports whether the board has received a device trigger.
+func (s Status) DTAS() bool { return s&StatusDTAS != 0 }
+
+// DCAS reports whether the board has received a device clear.
+func (s Status) DCAS() bool { return s&StatusDCAS != 0 }
+
+// StatusProvider is implemented by Transports that can expose the raw
+// status word left behind by the most recent operation, such as the
+// linux-gpib backend's ibsta.
+type StatusProvider interface {
+	Status() Status
+}
+
+// Status returns the Status left behind by the most recent operation
+// against this Device, if the underlying Transport supports it.
+func (d *Device) Status() Status {
+	t, ok := d.transport.(StatusProvider)
+	if !ok {
+		return 0
+	}
+	return t.Status()
+}
+
+// Sentinel errors for each linux-gpib IBERR code, so callers can tell
+// e.g. a timeout apart from "no listeners" or "board not CIC" with
+// errors.Is, instead of matching against an error string.
+var (
+	// ErrEDVR: a system (errno) error occurred; see the wrapped error
+	// for the underlying syscall.Errno.
+	ErrEDVR = errors.New("gpib: system error")
+
+	// ErrECIC: the board needs to be controller-in-charge, but is not.
+	ErrECIC = errors.New("gpib: interface board needs to be controller-in-charge, but is not")
+
+	// ErrENOL: a write was attempted, but there are no listeners
+	// currently addressed.
+	ErrENOL = errors.New("gpib: attempted to write data or command bytes, but there are no listeners currently addressed")
+
+	// ErrEADR: the board failed to address itself properly before
+	// starting an I/O operation.
+	ErrEADR = errors.New("gpib: interface board has failed to address itself properly before starting an io operation")
+
+	// ErrEARG: the arguments to the function call were invalid.
+	ErrEARG = errors.New("gpib: arguments to the function call were invalid")
+
+	// ErrESAC: the board needs to be the system controller, but is
+	// not.
+	ErrESAC = errors.New("gpib: interface board needs to be system controller, but is not")
+
+	// ErrEABO: the read or write of data bytes was aborted.
+	ErrEABO = errors.New("gpib: read or write of data bytes has been aborted")
+
+	// ErrENEB: the interface board does not exist.
+	ErrENEB = errors.New("gpib: interface board does not exist")
+
+	// ErrEDMA: a DMA error occurred.
+	ErrEDMA = errors.New("gpib: error performing DMA")
+
+	// ErrEOIP: the function call can not proceed because an
+	// asynchronous I/O operation is in progress.
+	ErrEOIP = errors.New("gpib: function call can not proceed due to an asynchronous IO operation")
+
+	// ErrECAP: the GPIB board lacks the desired capability.
+	ErrECAP = errors.New("gpib: GPIB board lacks desired capability")
+
+	// ErrEFSO: a filesystem error occurred.
+	ErrEFSO = errors.New("gpib: file system error")
+
+	// ErrEBUS: a command error occurred during a device call.
+	ErrEBUS = errors.New("gpib: command error during device call")
+
+	// ErrETMO: the operation timed out.
+	ErrETMO = errors.New("gpib: operation timed out")
+
+	// ErrESTB: serial poll status bytes have been lost.
+	ErrESTB = errors.New("gpib: serial poll status bytes have been lost")
+
+	// ErrESRQ: the serial poll request service line is stuck on.
+	ErrESRQ = errors.New("gpib: serial poll request service line is stuck on")
+
+	// ErrETAB: a table problem occurred (too many devices for the
+	// configured table size).
+	ErrETAB = errors.New("gpib: table problem")
+)
+
+// IsTimeout reports whether err indicates a GPIB operation timed out.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrETMO)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,54 @@
+// {{{ Copyright (c) Paul R. Tagliamonte <paul@k3xec.com>, 2021
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE. }}}
+
+package gpib
+
+// Transport is the set of bus operations a gpib.Device needs from whatever
+// is actually talking to the instrument. The default Transport (built by
+// Open) drives linux-gpib over cgo, but anything satisfying this interface
+// can be handed to NewDevice -- for instance a Prologix GPIB-USB/
+// GPIB-Ethernet controller, which moves bytes over a serial port or TCP
+// socket and makes no linux-gpib calls. This package itself is still
+// built with cgo and linked against linux-gpib, whichever Transport is
+// used.
+//
+// hp437.Device and future instrument drivers are written against
+// gpib.Device, and never need to know which Transport is underneath.
+type Transport interface {
+	// Write writes buf to the currently addressed device.
+	Write(buf []byte) (int, error)
+
+	// Read reads from the currently addressed device into buf.
+	Read(buf []byte) (int, error)
+
+	// Local returns the addressed device to local (front panel) control.
+	Local() error
+
+	// Trigger issues a GPIB group execute trigger to the addressed device.
+	Trigger() error
+
+	// SerialPoll reads the addressed device's serial poll status byte.
+	SerialPoll() (byte, error)
+
+	// Close releases any resources held by the Transport.
+	Close() error
+}
+
+// vim: foldmethod=marker